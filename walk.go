@@ -5,8 +5,8 @@
 package fswalk
 
 import (
+	"context"
 	"io/fs"
-	"path"
 )
 
 // WalkDirFunc is the type of the function called by WalkDir to visit
@@ -58,38 +58,6 @@ type WalkDirFunc func(path string, d fs.DirEntry, err error) error
 
 type DoneDirFunc func(path string, d fs.DirEntry) error
 
-// walkDir recursively descends path, calling walkDirFn and doneDirFn
-func walkDir(fsys fs.FS, name string, d fs.DirEntry, walkDirFn WalkDirFunc, doneDirFn DoneDirFunc) error {
-	if err := walkDirFn(name, d, nil); err != nil || !d.IsDir() {
-		if err == fs.SkipDir && d.IsDir() {
-			// Successfully skipped directory.
-			err = nil
-		}
-		return err
-	}
-
-	dirs, err := fs.ReadDir(fsys, name)
-	if err != nil {
-		// Second call, to report ReadDir error.
-		err = walkDirFn(name, d, err)
-		if err != nil {
-			return err
-		}
-	}
-
-	for _, d1 := range dirs {
-		name1 := path.Join(name, d1.Name())
-		if err := walkDir(fsys, name1, d1, walkDirFn, doneDirFn); err != nil {
-			if err == fs.SkipDir {
-				return nil
-			}
-			return err
-		}
-	}
-
-	return doneDirFn(name, d)
-}
-
 // WalkDir walks the file tree rooted at root, calling walkDirFn for each file or
 // directory in the tree, including root. doneDirFn is called any time a directory
 // has been walked.
@@ -104,17 +72,19 @@ func walkDir(fsys fs.FS, name string, d fs.DirEntry, walkDirFn WalkDirFunc, done
 //
 // WalkDir does not follow symbolic links found in directories,
 // but if root itself is a symbolic link, its target will be walked.
+//
+// WalkDir is a thin wrapper around WalkDirContext using
+// context.Background(); use WalkDirContext directly if the walk should be
+// cancellable.
 func WalkDir(fsys fs.FS, root string, walkDirFn WalkDirFunc, doneDirFn DoneDirFunc) error {
-	info, err := fs.Stat(fsys, root)
-	if err != nil {
-		err = walkDirFn(root, nil, err)
-	} else {
-		err = walkDir(fsys, root, &statDirEntry{info}, walkDirFn, doneDirFn)
-	}
-	if err == fs.SkipDir {
-		return nil
-	}
-	return err
+	return WalkDirContext(context.Background(), fsys, root,
+		func(ctx context.Context, path string, d fs.DirEntry, err error) error {
+			return walkDirFn(path, d, err)
+		},
+		func(ctx context.Context, path string, d fs.DirEntry) error {
+			return doneDirFn(path, d)
+		},
+	)
 }
 
 type statDirEntry struct {
@@ -0,0 +1,80 @@
+package fswalk
+
+import (
+	"context"
+	"io/fs"
+	"path"
+)
+
+// WalkDirFuncCtx is the context-aware counterpart of WalkDirFunc. It
+// behaves identically, except it additionally receives the ctx passed to
+// WalkDirContext so that callers doing per-file network I/O (e.g.
+// uploading a file while walking) can propagate cancellation.
+type WalkDirFuncCtx func(ctx context.Context, path string, d fs.DirEntry, err error) error
+
+// DoneDirFuncCtx is the context-aware counterpart of DoneDirFunc.
+type DoneDirFuncCtx func(ctx context.Context, path string, d fs.DirEntry) error
+
+// walkDirCtx recursively descends path, calling walkDirFn and doneDirFn,
+// checking ctx before each of them and before every fs.ReadDir.
+func walkDirCtx(ctx context.Context, fsys fs.FS, name string, d fs.DirEntry, walkDirFn WalkDirFuncCtx, doneDirFn DoneDirFuncCtx) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := walkDirFn(ctx, name, d, nil); err != nil || !d.IsDir() {
+		if err == fs.SkipDir && d.IsDir() {
+			// Successfully skipped directory.
+			err = nil
+		}
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	dirs, err := fs.ReadDir(fsys, name)
+	if err != nil {
+		// Second call, to report ReadDir error.
+		err = walkDirFn(ctx, name, d, err)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, d1 := range dirs {
+		name1 := path.Join(name, d1.Name())
+		if err := walkDirCtx(ctx, fsys, name1, d1, walkDirFn, doneDirFn); err != nil {
+			if err == fs.SkipDir {
+				return nil
+			}
+			return err
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return doneDirFn(ctx, name, d)
+}
+
+// WalkDirContext walks the file tree rooted at root like WalkDir, checking
+// ctx.Done() before each walkDirFn/doneDirFn invocation and before every
+// fs.ReadDir, returning ctx.Err() promptly once ctx is done.
+//
+// It is otherwise identical to WalkDir: see its documentation for the
+// walk order and the fs.SkipDir contract.
+func WalkDirContext(ctx context.Context, fsys fs.FS, root string, walkDirFn WalkDirFuncCtx, doneDirFn DoneDirFuncCtx) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	info, err := fs.Stat(fsys, root)
+	if err != nil {
+		err = walkDirFn(ctx, root, nil, err)
+	} else {
+		err = walkDirCtx(ctx, fsys, root, &statDirEntry{info}, walkDirFn, doneDirFn)
+	}
+	if err == fs.SkipDir {
+		return nil
+	}
+	return err
+}
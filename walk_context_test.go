@@ -0,0 +1,64 @@
+package fswalk
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWalkDirContextCancelStopsPromptly(t *testing.T) {
+	mfs := fstest.MapFS{
+		"a/f0": &fstest.MapFile{Data: []byte("x")},
+		"a/f1": &fstest.MapFile{Data: []byte("x")},
+		"b/f0": &fstest.MapFile{Data: []byte("x")},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var visited int
+	err := WalkDirContext(ctx, mfs, ".",
+		func(ctx context.Context, path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			visited++
+			if path == "a" {
+				cancel()
+			}
+			return nil
+		},
+		func(ctx context.Context, path string, d fs.DirEntry) error { return nil },
+	)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got err %v, want context.Canceled", err)
+	}
+	// The walk must not have reached "b", the sibling after "a".
+	if visited > 3 {
+		t.Errorf("visited %d entries after cancellation, walk did not stop promptly", visited)
+	}
+}
+
+func TestWalkDirStillWorksUnmodified(t *testing.T) {
+	mfs := fstest.MapFS{"a/f0": &fstest.MapFile{Data: []byte("x")}}
+	var got []string
+	err := WalkDir(mfs, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		got = append(got, path)
+		return nil
+	}, func(path string, d fs.DirEntry) error { return nil })
+	if err != nil {
+		t.Fatalf("WalkDir: %v", err)
+	}
+	want := []string{".", "a", "a/f0"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package fswalk
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"runtime"
+	"sync"
+)
+
+// ParallelOptions configures WalkDirParallel.
+type ParallelOptions struct {
+	// Workers bounds the number of fs.ReadDir calls in flight at once. If
+	// zero, it defaults to runtime.GOMAXPROCS(0). It does not bound the
+	// number of goroutines: one goroutine is started per directory walked.
+	Workers int
+}
+
+// WalkDirParallel walks the file tree rooted at root like WalkDir, but
+// walks different subtrees in separate goroutines, with at most
+// opts.Workers fs.ReadDir calls in flight at once, so that I/O-bound trees
+// can be walked concurrently.
+//
+// walkDirFn calls for entries of the same directory are always serialized,
+// in the order fs.ReadDir returned them, so the fs.SkipDir contract
+// documented on WalkDirFunc continues to hold within a directory: returning
+// it for a directory entry skips that directory, and returning it for a
+// non-directory entry skips the rest of its containing directory (and, as
+// in WalkDir, that directory's doneDirFn is not called in that case
+// either). This serialization is required for the fs.SkipDir contract to
+// make sense, so there is no option to relax it: different directories may
+// still be walked concurrently and their walkDirFn/doneDirFn calls may
+// interleave, but siblings never do.
+//
+// doneDirFn for a directory is called only after every descendant of that
+// directory has been fully walked (a post-order barrier per subtree).
+//
+// ctx is checked before every fs.ReadDir; once ctx is done, no new
+// directories are read and WalkDirParallel returns ctx.Err() once the
+// in-flight work drains.
+//
+// The first non-nil error returned by walkDirFn or doneDirFn (other than
+// fs.SkipDir) cancels the remaining work and is returned from
+// WalkDirParallel. If multiple goroutines report an error concurrently,
+// one of them (arbitrarily) wins.
+func WalkDirParallel(ctx context.Context, fsys fs.FS, root string, opts ParallelOptions, walkDirFn WalkDirFunc, doneDirFn DoneDirFunc) error {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	info, err := fs.Stat(fsys, root)
+	if err != nil {
+		err = walkDirFn(root, nil, err)
+		if err == fs.SkipDir {
+			return nil
+		}
+		return err
+	}
+
+	w := &parallelWalker{
+		ctx:       ctx,
+		fsys:      fsys,
+		sem:       make(chan struct{}, workers),
+		walkDirFn: walkDirFn,
+		doneDirFn: doneDirFn,
+	}
+	rootEntry := fs.DirEntry(&statDirEntry{info})
+	if _, descend := w.visit(root, rootEntry); descend {
+		w.descend(root, rootEntry)
+	}
+	if err := w.firstErr(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// parallelWalker holds the state shared by all goroutines participating in
+// a single WalkDirParallel call.
+type parallelWalker struct {
+	ctx       context.Context
+	fsys      fs.FS
+	sem       chan struct{} // bounds concurrent fs.ReadDir calls
+	walkDirFn WalkDirFunc
+	doneDirFn DoneDirFunc
+
+	mu      sync.Mutex
+	err     error
+	aborted bool
+}
+
+func (w *parallelWalker) firstErr() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// fail records err as the walk's result if no error has been recorded yet,
+// and marks the walk as aborted so new work is skipped.
+func (w *parallelWalker) fail(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+	w.aborted = true
+}
+
+func (w *parallelWalker) isAborted() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.aborted {
+		return true
+	}
+	return w.ctx.Err() != nil
+}
+
+// visit calls walkDirFn for name and translates its result the way
+// WalkDir's recursive walkDir does: nil means continue, with descend
+// reporting whether name's subtree should be read. fs.SkipDir on a
+// directory means don't descend into it, but is not itself an error.
+// fs.SkipDir on a non-directory entry means stop processing the rest of
+// name's siblings (it is returned as-is so descend's loop can detect
+// this), without failing the walk. Any other non-nil error fails the
+// whole walk via fail.
+func (w *parallelWalker) visit(name string, d fs.DirEntry) (err error, descend bool) {
+	err = w.walkDirFn(name, d, nil)
+	switch {
+	case err == fs.SkipDir && d.IsDir():
+		return nil, false
+	case err == fs.SkipDir:
+		return fs.SkipDir, false
+	case err != nil:
+		w.fail(err)
+		return err, false
+	default:
+		return nil, d.IsDir()
+	}
+}
+
+// descend reads name's directory entries and walks them, then calls
+// doneDirFn once every descendant has been walked. The caller must already
+// have established (via visit, or as the root) that name is a directory to
+// be read. descend only returns once the whole subtree rooted at name has
+// been walked, making it safe to use as the post-order barrier doneDirFn
+// relies on.
+func (w *parallelWalker) descend(name string, d fs.DirEntry) {
+	if w.isAborted() {
+		return
+	}
+
+	w.sem <- struct{}{}
+	dirs, err := fs.ReadDir(w.fsys, name)
+	<-w.sem
+	if err != nil {
+		if err = w.walkDirFn(name, d, err); err != nil {
+			if err != fs.SkipDir {
+				w.fail(err)
+			}
+			return
+		}
+	}
+
+	// walkDirFn calls for name's entries are always serialized, in the
+	// order fs.ReadDir returned them, so that a non-directory entry
+	// returning fs.SkipDir can cleanly stop the rest of this loop (see
+	// visit); only the subsequent descend into each directory entry runs
+	// concurrently with its siblings.
+	var children sync.WaitGroup
+	skippedSiblings := false
+	for _, d1 := range dirs {
+		if w.isAborted() {
+			break
+		}
+		name1 := path.Join(name, d1.Name())
+		err, descend := w.visit(name1, d1)
+		if err == fs.SkipDir {
+			// A non-directory entry asked to skip the rest of name's
+			// entries; as in WalkDir, name's own doneDirFn is not
+			// called either.
+			skippedSiblings = true
+			break
+		}
+		if err != nil {
+			// Already recorded via fail inside visit.
+			break
+		}
+		if !descend {
+			continue
+		}
+		children.Add(1)
+		d1 := d1
+		go func() {
+			defer children.Done()
+			w.descend(name1, d1)
+		}()
+	}
+	children.Wait()
+
+	if skippedSiblings || w.isAborted() {
+		return
+	}
+	if err := w.doneDirFn(name, d); err != nil && err != fs.SkipDir {
+		w.fail(err)
+	}
+}
@@ -0,0 +1,227 @@
+package fswalk
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// delayFS wraps an fs.FS and sleeps for delay before every ReadDir, to
+// simulate an I/O-bound file system for the parallel speedup test.
+type delayFS struct {
+	fs.FS
+	delay time.Duration
+}
+
+func (d delayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	time.Sleep(d.delay)
+	return fs.ReadDir(d.FS, name)
+}
+
+func makeWideMapFS(dirs, filesPerDir int) fstest.MapFS {
+	m := fstest.MapFS{}
+	for i := 0; i < dirs; i++ {
+		for j := 0; j < filesPerDir; j++ {
+			name := rootRelPath(i, j)
+			m[name] = &fstest.MapFile{Data: []byte("x")}
+		}
+	}
+	return m
+}
+
+func rootRelPath(dir, file int) string {
+	const letters = "abcdefghijklmnopqrstuvwxyz"
+	return string(letters[dir%len(letters)]) + "/f" + string(rune('0'+file))
+}
+
+func TestWalkDirParallelSpeedup(t *testing.T) {
+	const dirs = 8
+	const delay = 20 * time.Millisecond
+	mfs := delayFS{FS: makeWideMapFS(dirs, 2), delay: delay}
+
+	start := time.Now()
+	err := WalkDirParallel(context.Background(), mfs, ".", ParallelOptions{Workers: dirs},
+		func(path string, d fs.DirEntry, err error) error { return err },
+		func(path string, d fs.DirEntry) error { return nil },
+	)
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("WalkDirParallel: %v", err)
+	}
+	// Sequential reads of the root plus dirs subdirectories would take
+	// roughly (dirs+1)*delay; with enough workers it should be much
+	// closer to a couple of delay units.
+	if elapsed > delay*time.Duration(dirs) {
+		t.Errorf("WalkDirParallel took %v, want well under %v (no parallelism?)", elapsed, delay*time.Duration(dirs))
+	}
+}
+
+func TestWalkDirParallelPerDirOrder(t *testing.T) {
+	mfs := makeWideMapFS(4, 3)
+
+	var mu sync.Mutex
+	var order []string
+	err := WalkDirParallel(context.Background(), mfs, ".", ParallelOptions{},
+		func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() {
+				mu.Lock()
+				order = append(order, path)
+				mu.Unlock()
+			}
+			return nil
+		},
+		func(path string, d fs.DirEntry) error { return nil },
+	)
+	if err != nil {
+		t.Fatalf("WalkDirParallel: %v", err)
+	}
+
+	// Compare against the sequential WalkDir's order, directory by
+	// directory: file names within any single directory must appear in
+	// the same relative order, even though whole directories may have
+	// interleaved.
+	var want []string
+	if err := WalkDir(mfs, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			want = append(want, path)
+		}
+		return nil
+	}, func(path string, d fs.DirEntry) error { return nil }); err != nil {
+		t.Fatalf("WalkDir: %v", err)
+	}
+
+	byDir := func(paths []string) map[string][]string {
+		m := map[string][]string{}
+		for _, p := range paths {
+			dir := p[:1]
+			m[dir] = append(m[dir], p)
+		}
+		return m
+	}
+	gotByDir, wantByDir := byDir(order), byDir(want)
+	for dir, wantFiles := range wantByDir {
+		gotFiles := gotByDir[dir]
+		if len(gotFiles) != len(wantFiles) {
+			t.Fatalf("dir %q: got %v, want %v", dir, gotFiles, wantFiles)
+		}
+		for i := range wantFiles {
+			if gotFiles[i] != wantFiles[i] {
+				t.Errorf("dir %q: got order %v, want %v", dir, gotFiles, wantFiles)
+				break
+			}
+		}
+	}
+}
+
+func TestWalkDirParallelErrorCancels(t *testing.T) {
+	mfs := makeWideMapFS(6, 2)
+	boom := fs.ErrInvalid
+	var visited int32
+
+	err := WalkDirParallel(context.Background(), mfs, ".", ParallelOptions{Workers: 4},
+		func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if path == "c" {
+				return boom
+			}
+			atomic.AddInt32(&visited, 1)
+			return nil
+		},
+		func(path string, d fs.DirEntry) error { return nil },
+	)
+	if err != boom {
+		t.Fatalf("got err %v, want %v", err, boom)
+	}
+	// Only ".", a, b and their two files each can have been visited: the
+	// root's loop stops at c, so d, e and f are never reached.
+	if n := atomic.LoadInt32(&visited); n > 7 {
+		t.Errorf("visited %d entries, want at most 7 after the error cancelled the walk", n)
+	}
+}
+
+func TestWalkDirParallelContextCancelStopsPromptly(t *testing.T) {
+	mfs := makeWideMapFS(6, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var visited int32
+	err := WalkDirParallel(ctx, mfs, ".", ParallelOptions{Workers: 1},
+		func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if path == "c" {
+				cancel()
+			}
+			atomic.AddInt32(&visited, 1)
+			return nil
+		},
+		func(path string, d fs.DirEntry) error { return nil },
+	)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got err %v, want context.Canceled", err)
+	}
+	// Only ".", a, b, c and the two files each of a and b can have been
+	// visited: the root's loop stops after c, so d, e and f are never reached.
+	if n := atomic.LoadInt32(&visited); n > 8 {
+		t.Errorf("visited %d entries, want at most 8 after cancellation", n)
+	}
+}
+
+func TestWalkDirParallelSkipDirOnFile(t *testing.T) {
+	mfs := fstest.MapFS{
+		"a/f0": &fstest.MapFile{Data: []byte("x")},
+		"a/f1": &fstest.MapFile{Data: []byte("x")},
+		"a/f2": &fstest.MapFile{Data: []byte("x")},
+	}
+
+	var mu sync.Mutex
+	var visited []string
+	var doneA bool
+	err := WalkDirParallel(context.Background(), mfs, ".", ParallelOptions{},
+		func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if path == "a/f0" {
+				mu.Lock()
+				visited = append(visited, path)
+				mu.Unlock()
+				return fs.SkipDir
+			}
+			if !d.IsDir() {
+				mu.Lock()
+				visited = append(visited, path)
+				mu.Unlock()
+			}
+			return nil
+		},
+		func(path string, d fs.DirEntry) error {
+			if path == "a" {
+				doneA = true
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("WalkDirParallel returned %v, want nil (fs.SkipDir must not leak)", err)
+	}
+	if len(visited) != 1 || visited[0] != "a/f0" {
+		t.Errorf("visited %v, want only [a/f0] (f1/f2 should be skipped)", visited)
+	}
+	if doneA {
+		t.Errorf("doneDirFn(\"a\", ...) was called, want it skipped along with a's remaining entries")
+	}
+}
@@ -0,0 +1,100 @@
+package fswalk
+
+import (
+	"io/fs"
+	"path"
+)
+
+// ChildResult carries the outcome of walking one entry of a directory,
+// as passed to the reduceFn of WalkDirReduce.
+type ChildResult[T any] struct {
+	// DirEntry is the fs.DirEntry of the child, as passed to mapFn (or
+	// reduceFn, if the child is itself a directory).
+	DirEntry fs.DirEntry
+
+	// Value is the value mapFn (for a file) or reduceFn (for a
+	// directory) returned for this child, alongside Err; it is passed
+	// through as returned even if Err is non-nil.
+	Value T
+
+	// Err is the error mapFn or reduceFn returned for this child, if
+	// any.
+	Err error
+}
+
+// WalkDirReduce walks the file tree rooted at root, computing a value of
+// type T for every file via mapFn, and a value of type T for every
+// directory via reduceFn applied to the ChildResults of its children. It
+// returns the value computed by reduceFn for root.
+//
+// Unlike WalkDir, WalkDirReduce does not take separate walkDirFn and
+// doneDirFn callbacks: mapFn plays the role of walkDirFn for files, and
+// reduceFn plays the role of doneDirFn for directories, but both return a
+// value alongside an error so callers can compute recursive aggregates
+// (directory sizes, content hashes, file counts, ...) in a single walk,
+// without a second pass or external maps keyed by path.
+//
+// mapFn is called for every non-directory entry in the tree, and is also
+// given a chance at every directory whose fs.ReadDir fails, mirroring the
+// err argument and the "second call" convention documented on WalkDirFunc:
+// d is the directory's own fs.DirEntry and err is the error from ReadDir
+// (or, for root, err is the error from the initial fs.Stat and d is nil).
+// If mapFn returns a non-nil
+// error in that case, its value and error become the directory's own
+// result, exactly as for a failed non-directory entry, and reduceFn is not
+// called for it; if mapFn swallows the error by returning nil, reduceFn is
+// still called for the directory, with no children. Otherwise reduceFn is
+// called once per directory, after every child has been visited, with one
+// ChildResult per child in lexical order.
+//
+// If mapFn or reduceFn returns a non-nil error for an entry other than
+// root, that error is recorded in the corresponding ChildResult and
+// passed to the parent's reduceFn rather than stopping the walk; it is up
+// to reduceFn to decide whether to propagate it. The error returned for
+// root (the second return value of WalkDirReduce) is whatever reduceFn
+// (or mapFn, if root is not a directory, or if root's own ReadDir failed
+// and mapFn did not swallow the error) returned for root itself.
+func WalkDirReduce[T any](
+	fsys fs.FS,
+	root string,
+	mapFn func(path string, d fs.DirEntry, err error) (T, error),
+	reduceFn func(path string, d fs.DirEntry, children []ChildResult[T]) (T, error),
+) (T, error) {
+	info, err := fs.Stat(fsys, root)
+	if err != nil {
+		return mapFn(root, nil, err)
+	}
+	return walkDirReduce(fsys, root, &statDirEntry{info}, mapFn, reduceFn)
+}
+
+func walkDirReduce[T any](
+	fsys fs.FS,
+	name string,
+	d fs.DirEntry,
+	mapFn func(path string, d fs.DirEntry, err error) (T, error),
+	reduceFn func(path string, d fs.DirEntry, children []ChildResult[T]) (T, error),
+) (T, error) {
+	if !d.IsDir() {
+		return mapFn(name, d, nil)
+	}
+
+	dirs, err := fs.ReadDir(fsys, name)
+	if err != nil {
+		// Second call, to report the ReadDir error, mirroring
+		// WalkDirFunc's documented convention.
+		v, err := mapFn(name, d, err)
+		if err != nil {
+			return v, err
+		}
+		return reduceFn(name, d, nil)
+	}
+
+	children := make([]ChildResult[T], len(dirs))
+	for i, d1 := range dirs {
+		name1 := path.Join(name, d1.Name())
+		v, err := walkDirReduce(fsys, name1, d1, mapFn, reduceFn)
+		children[i] = ChildResult[T]{DirEntry: d1, Value: v, Err: err}
+	}
+
+	return reduceFn(name, d, children)
+}
@@ -0,0 +1,170 @@
+package fswalk
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+// readDirErrFS wraps an fstest.MapFS, failing ReadDir for one path so
+// tests can exercise WalkDirReduce's ReadDir-failure handling.
+type readDirErrFS struct {
+	fstest.MapFS
+	errOn string
+	err   error
+}
+
+func (f readDirErrFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name == f.errOn {
+		return nil, f.err
+	}
+	return fs.ReadDir(f.MapFS, name)
+}
+
+func TestWalkDirReduceFileCount(t *testing.T) {
+	mfs := fstest.MapFS{
+		"a/f0":   &fstest.MapFile{Data: []byte("x")},
+		"a/f1":   &fstest.MapFile{Data: []byte("x")},
+		"a/b/f2": &fstest.MapFile{Data: []byte("x")},
+		"c/f3":   &fstest.MapFile{Data: []byte("x")},
+	}
+
+	total, err := WalkDirReduce(mfs, ".",
+		func(path string, d fs.DirEntry, err error) (int, error) {
+			return 1, err
+		},
+		func(path string, d fs.DirEntry, children []ChildResult[int]) (int, error) {
+			sum := 0
+			for _, c := range children {
+				if c.Err != nil {
+					return 0, c.Err
+				}
+				sum += c.Value
+			}
+			return sum, nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("WalkDirReduce: %v", err)
+	}
+	if total != 4 {
+		t.Errorf("got %d files, want 4", total)
+	}
+}
+
+func TestWalkDirReduceDirSize(t *testing.T) {
+	mfs := fstest.MapFS{
+		"a/f0": &fstest.MapFile{Data: []byte("12345")},
+		"a/f1": &fstest.MapFile{Data: []byte("12")},
+		"b/f2": &fstest.MapFile{Data: []byte("1")},
+	}
+
+	size, err := WalkDirReduce(mfs, ".",
+		func(path string, d fs.DirEntry, err error) (int64, error) {
+			if err != nil {
+				return 0, err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return 0, err
+			}
+			return info.Size(), nil
+		},
+		func(path string, d fs.DirEntry, children []ChildResult[int64]) (int64, error) {
+			var sum int64
+			for _, c := range children {
+				if c.Err != nil {
+					return 0, c.Err
+				}
+				sum += c.Value
+			}
+			return sum, nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("WalkDirReduce: %v", err)
+	}
+	if size != 8 {
+		t.Errorf("got size %d, want 8", size)
+	}
+}
+
+func TestWalkDirReduceReadDirErrorCallsMapFn(t *testing.T) {
+	boom := errors.New("boom")
+	ffs := readDirErrFS{
+		MapFS: fstest.MapFS{"a/f0": &fstest.MapFile{Data: []byte("x")}},
+		errOn: "a",
+		err:   boom,
+	}
+
+	var mapFnCalls []string
+	_, err := WalkDirReduce(ffs, ".",
+		func(path string, d fs.DirEntry, err error) (int, error) {
+			mapFnCalls = append(mapFnCalls, path)
+			if path == "a" {
+				if err != boom {
+					t.Errorf("mapFn(%q): got err %v, want %v", path, err, boom)
+				}
+				return 0, nil // swallow the error
+			}
+			return 1, err
+		},
+		func(path string, d fs.DirEntry, children []ChildResult[int]) (int, error) {
+			if path == "a" && len(children) != 0 {
+				t.Errorf("reduceFn(%q): got %d children, want 0 after a swallowed ReadDir error", path, len(children))
+			}
+			sum := 0
+			for _, c := range children {
+				sum += c.Value
+			}
+			return sum, nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("WalkDirReduce: %v", err)
+	}
+
+	found := false
+	for _, p := range mapFnCalls {
+		if p == "a" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("mapFn was never called for %q, want a call reporting the ReadDir error", "a")
+	}
+}
+
+func TestWalkDirReduceReadDirErrorPropagates(t *testing.T) {
+	boom := errors.New("boom")
+	ffs := readDirErrFS{
+		MapFS: fstest.MapFS{"a/f0": &fstest.MapFile{Data: []byte("x")}},
+		errOn: "a",
+		err:   boom,
+	}
+
+	reduceCalledForA := false
+	_, err := WalkDirReduce(ffs, ".",
+		func(path string, d fs.DirEntry, err error) (int, error) {
+			return 0, err
+		},
+		func(path string, d fs.DirEntry, children []ChildResult[int]) (int, error) {
+			if path == "a" {
+				reduceCalledForA = true
+			}
+			for _, c := range children {
+				if c.Err != nil {
+					return 0, c.Err
+				}
+			}
+			return 0, nil
+		},
+	)
+	if !errors.Is(err, boom) {
+		t.Fatalf("got err %v, want %v", err, boom)
+	}
+	if reduceCalledForA {
+		t.Errorf("reduceFn was called for %q despite mapFn propagating the ReadDir error", "a")
+	}
+}
@@ -0,0 +1,114 @@
+//go:build go1.23
+
+package fswalk
+
+import (
+	"io/fs"
+	"iter"
+	"path"
+)
+
+// Kind distinguishes the different events WalkDirSeq yields for a given
+// path.
+type Kind int
+
+const (
+	// KindPre is the pre-order visit of path, analogous to a walkDirFn
+	// call with a nil err in WalkDir.
+	KindPre Kind = iota
+
+	// KindReadErr reports an error reading path: either the initial
+	// fs.Stat on root, or a directory's fs.ReadDir, matching the two
+	// cases documented on WalkDirFunc.
+	KindReadErr
+
+	// KindDone is the post-order visit of a directory, once every entry
+	// in it has been walked, analogous to a DoneDirFunc call.
+	KindDone
+)
+
+// WalkEntry is the value WalkDirSeq yields for every event in the walk.
+type WalkEntry struct {
+	// Path is the path being reported, with the same prefixing rules as
+	// the path argument to WalkDirFunc.
+	Path string
+
+	// DirEntry is the fs.DirEntry for Path, or nil if Kind is
+	// KindReadErr and the failure was the initial fs.Stat on root.
+	DirEntry fs.DirEntry
+
+	// Kind says which event this entry represents.
+	Kind Kind
+
+	skip *bool
+}
+
+// SkipDir, called during the body of a range over WalkDirSeq for a
+// KindPre entry whose DirEntry is a directory, makes WalkDirSeq skip that
+// directory's contents without aborting the rest of the walk. It is the
+// WalkDirSeq equivalent of a WalkDirFunc returning fs.SkipDir: a plain
+// break in a range-over-func loop instead stops the walk entirely, so
+// SkipDir exists to thread that narrower signal back into the iterator.
+// Calling it on any other entry has no effect.
+func SkipDir(e *WalkEntry) {
+	if e.skip != nil {
+		*e.skip = true
+	}
+}
+
+// WalkDirSeq walks the file tree rooted at root, like WalkDir, but
+// returns a range-over-func iterator instead of taking callbacks:
+//
+//	for e, err := range WalkDirSeq(fsys, ".") {
+//		if err != nil {
+//			continue
+//		}
+//		switch e.Kind {
+//		case fswalk.KindPre:
+//			// visiting e.Path, e.DirEntry
+//		case fswalk.KindDone:
+//			// e.Path's subtree is fully walked
+//		}
+//	}
+//
+// Files are walked in lexical order, as in WalkDir. Breaking out of the
+// range loop aborts the walk. Call SkipDir on a KindPre entry to skip
+// that directory's contents while continuing the walk elsewhere.
+func WalkDirSeq(fsys fs.FS, root string) iter.Seq2[WalkEntry, error] {
+	return func(yield func(WalkEntry, error) bool) {
+		info, err := fs.Stat(fsys, root)
+		if err != nil {
+			yield(WalkEntry{Path: root, Kind: KindReadErr}, err)
+			return
+		}
+		walkDirSeq(fsys, root, &statDirEntry{info}, yield)
+	}
+}
+
+// walkDirSeq recursively descends name, yielding entries for it and its
+// descendants. It reports whether the walk should continue: false means
+// yield asked to stop (the range loop broke), and callers must themselves
+// stop and propagate false without yielding further.
+func walkDirSeq(fsys fs.FS, name string, d fs.DirEntry, yield func(WalkEntry, error) bool) bool {
+	skip := new(bool)
+	if !yield(WalkEntry{Path: name, DirEntry: d, Kind: KindPre, skip: skip}, nil) {
+		return false
+	}
+	if !d.IsDir() || *skip {
+		return true
+	}
+
+	dirs, err := fs.ReadDir(fsys, name)
+	if err != nil {
+		return yield(WalkEntry{Path: name, DirEntry: d, Kind: KindReadErr}, err)
+	}
+
+	for _, d1 := range dirs {
+		name1 := path.Join(name, d1.Name())
+		if !walkDirSeq(fsys, name1, d1, yield) {
+			return false
+		}
+	}
+
+	return yield(WalkEntry{Path: name, DirEntry: d, Kind: KindDone}, nil)
+}
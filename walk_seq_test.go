@@ -0,0 +1,100 @@
+//go:build go1.23
+
+package fswalk
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestWalkDirSeqVisitsInLexicalOrder(t *testing.T) {
+	mfs := fstest.MapFS{
+		"a/f0": &fstest.MapFile{Data: []byte("x")},
+		"a/f1": &fstest.MapFile{Data: []byte("x")},
+		"b/f0": &fstest.MapFile{Data: []byte("x")},
+	}
+
+	var pre, done []string
+	for e, err := range WalkDirSeq(mfs, ".") {
+		if err != nil {
+			t.Fatalf("WalkDirSeq: %v", err)
+		}
+		switch e.Kind {
+		case KindPre:
+			pre = append(pre, e.Path)
+		case KindDone:
+			done = append(done, e.Path)
+		}
+	}
+
+	wantPre := []string{".", "a", "a/f0", "a/f1", "b", "b/f0"}
+	if len(pre) != len(wantPre) {
+		t.Fatalf("got pre %v, want %v", pre, wantPre)
+	}
+	for i := range wantPre {
+		if pre[i] != wantPre[i] {
+			t.Fatalf("got pre %v, want %v", pre, wantPre)
+		}
+	}
+
+	wantDone := []string{"a", "b", "."}
+	if len(done) != len(wantDone) {
+		t.Fatalf("got done %v, want %v", done, wantDone)
+	}
+	for i := range wantDone {
+		if done[i] != wantDone[i] {
+			t.Fatalf("got done %v, want %v", done, wantDone)
+		}
+	}
+}
+
+func TestWalkDirSeqSkipDir(t *testing.T) {
+	mfs := fstest.MapFS{
+		"a/f0": &fstest.MapFile{Data: []byte("x")},
+		"b/f0": &fstest.MapFile{Data: []byte("x")},
+	}
+
+	var visited []string
+	for e, err := range WalkDirSeq(mfs, ".") {
+		if err != nil {
+			t.Fatalf("WalkDirSeq: %v", err)
+		}
+		if e.Kind != KindPre {
+			continue
+		}
+		visited = append(visited, e.Path)
+		if e.Path == "a" {
+			SkipDir(&e)
+		}
+	}
+
+	for _, p := range visited {
+		if p == "a/f0" {
+			t.Errorf("SkipDir(a) did not prevent visiting %q", p)
+		}
+	}
+}
+
+func TestWalkDirSeqBreakStopsWalk(t *testing.T) {
+	mfs := fstest.MapFS{
+		"a/f0": &fstest.MapFile{Data: []byte("x")},
+		"b/f0": &fstest.MapFile{Data: []byte("x")},
+	}
+
+	var visited []string
+	for e, err := range WalkDirSeq(mfs, ".") {
+		if err != nil {
+			t.Fatalf("WalkDirSeq: %v", err)
+		}
+		visited = append(visited, e.Path)
+		if e.Path == "a" {
+			break
+		}
+	}
+
+	for _, p := range visited {
+		if p == "b" {
+			t.Errorf("break did not stop the walk, visited %q afterward", p)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package fswalk
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+)
+
+// ErrSymlinkCycle is passed to walkDirFn when WalkDirWithOptions, with
+// FollowSymlinks enabled, encounters a symbolic link whose target it has
+// already visited. The caller decides how to handle it, exactly as for
+// any other err reported to a WalkDirFunc; returning it (or any other
+// non-nil, non-fs.SkipDir error) stops the walk.
+var ErrSymlinkCycle = errors.New("fswalk: symlink cycle detected")
+
+// ReadLinkFS is implemented by file systems that can resolve the target
+// of a symbolic link. It has the ReadLink method of the standard library's
+// fs.ReadLinkFS (added in Go 1.25), without its Lstat method, and is
+// consulted first on every Go version, so FollowSymlinks works the same way
+// regardless of which Go version fswalk is built with.
+type ReadLinkFS interface {
+	fs.FS
+	ReadLink(name string) (string, error)
+}
+
+// WalkDirOptions configures WalkDirWithOptions.
+type WalkDirOptions struct {
+	// FollowSymlinks makes WalkDirWithOptions descend into directories
+	// reached through a symbolic link, which WalkDir never does (see its
+	// documentation). Cycles are broken by tracking the resolved target
+	// of every symlink directory visited; a repeat is reported to
+	// walkDirFn as ErrSymlinkCycle instead of being walked again.
+	FollowSymlinks bool
+
+	// MaxDepth caps how many levels below root WalkDirWithOptions will
+	// descend; 0 means unlimited. It is a safety net against
+	// pathological trees, in particular deep or accidentally cyclical
+	// symlink chains that Readlink cannot resolve to a cycle (e.g.
+	// because each target is subtly different).
+	MaxDepth int
+
+	// Readlink resolves the target of the symbolic link entry named by
+	// path. If nil, WalkDirWithOptions uses fs.ReadLink on fsys (via
+	// ReadLinkFS) when fsys supports it, and reports an error to
+	// walkDirFn otherwise. Readlink is only consulted when
+	// FollowSymlinks is true.
+	Readlink func(fsys fs.FS, path string) (string, error)
+}
+
+// WalkDirWithOptions walks the file tree rooted at root like WalkDir, but
+// applies opts: see WalkDirOptions for the behavior it enables.
+func WalkDirWithOptions(fsys fs.FS, root string, opts WalkDirOptions, walkDirFn WalkDirFunc, doneDirFn DoneDirFunc) error {
+	info, err := fs.Stat(fsys, root)
+	if err != nil {
+		err = walkDirFn(root, nil, err)
+	} else {
+		w := &symlinkWalker{fsys: fsys, opts: opts, visited: map[string]bool{}}
+		err = w.walk(root, &statDirEntry{info}, 0, walkDirFn, doneDirFn)
+	}
+	if err == fs.SkipDir {
+		return nil
+	}
+	return err
+}
+
+type symlinkWalker struct {
+	fsys    fs.FS
+	opts    WalkDirOptions
+	visited map[string]bool // resolved, cleaned symlink targets already descended into
+}
+
+func (w *symlinkWalker) readlink(name string) (string, error) {
+	if w.opts.Readlink != nil {
+		return w.opts.Readlink(w.fsys, name)
+	}
+	return defaultReadlink(w.fsys, name)
+}
+
+// resolveSymlinkDir follows the symlink entry at name, if FollowSymlinks
+// is enabled and d is one, and reports the fs.DirEntry to walk in its
+// place. If the target has already been visited, it reports isCycle.
+func (w *symlinkWalker) resolveSymlinkDir(name string, d fs.DirEntry) (eff fs.DirEntry, isCycle bool, err error) {
+	if !w.opts.FollowSymlinks || d.Type()&fs.ModeSymlink == 0 {
+		return d, false, nil
+	}
+
+	target, err := w.readlink(name)
+	if err != nil {
+		return d, false, err
+	}
+	target = path.Clean(target)
+	if !path.IsAbs(target) {
+		target = path.Join(path.Dir(name), target)
+	}
+
+	info, err := fs.Stat(w.fsys, name) // stat follows the symlink for most fs.FS implementations
+	if err != nil {
+		return d, false, err
+	}
+	if !info.IsDir() {
+		return d, false, nil
+	}
+	if w.visited[target] {
+		return d, true, nil
+	}
+	w.visited[target] = true
+	return &statDirEntry{info}, false, nil
+}
+
+func (w *symlinkWalker) walk(name string, d fs.DirEntry, depth int, walkDirFn WalkDirFunc, doneDirFn DoneDirFunc) error {
+	eff, isCycle, resolveErr := w.resolveSymlinkDir(name, d)
+	if isCycle {
+		// The symlink itself is a leaf as far as d.IsDir() is concerned,
+		// but ErrSymlinkCycle stands in for the directory it would have
+		// led into, so fs.SkipDir here means "don't walk it" (a no-op,
+		// since we already aren't going to), not "skip the rest of the
+		// containing directory".
+		if err := walkDirFn(name, d, ErrSymlinkCycle); err != nil && err != fs.SkipDir {
+			return err
+		}
+		return nil
+	}
+	if resolveErr != nil {
+		if err := walkDirFn(name, d, resolveErr); err != nil && err != fs.SkipDir {
+			return err
+		}
+		return nil
+	}
+
+	if err := walkDirFn(name, d, nil); err != nil || !eff.IsDir() {
+		if err == fs.SkipDir && eff.IsDir() {
+			// Successfully skipped directory.
+			err = nil
+		}
+		return err
+	}
+
+	if w.opts.MaxDepth > 0 && depth >= w.opts.MaxDepth {
+		return doneDirFn(name, d)
+	}
+
+	dirs, err := fs.ReadDir(w.fsys, name)
+	if err != nil {
+		// Second call, to report ReadDir error.
+		if err = walkDirFn(name, d, err); err != nil {
+			return err
+		}
+	}
+
+	for _, d1 := range dirs {
+		name1 := path.Join(name, d1.Name())
+		if err := w.walk(name1, d1, depth+1, walkDirFn, doneDirFn); err != nil {
+			if err == fs.SkipDir {
+				return nil
+			}
+			return err
+		}
+	}
+
+	return doneDirFn(name, d)
+}
@@ -0,0 +1,20 @@
+//go:build !go1.25
+
+package fswalk
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// defaultReadlink resolves name's symlink target via ReadLinkFS, for Go
+// versions that predate the standard library's fs.ReadLink (Go 1.25).
+// Callers on such a toolchain whose fsys does not implement ReadLinkFS
+// must supply a custom WalkDirOptions.Readlink.
+func defaultReadlink(fsys fs.FS, name string) (string, error) {
+	rl, ok := fsys.(ReadLinkFS)
+	if !ok {
+		return "", errors.New("fswalk: fsys does not implement ReadLinkFS; supply WalkDirOptions.Readlink")
+	}
+	return rl.ReadLink(name)
+}
@@ -0,0 +1,15 @@
+//go:build go1.25
+
+package fswalk
+
+import "io/fs"
+
+// defaultReadlink resolves name's symlink target via ReadLinkFS if fsys
+// implements it, and otherwise using the standard library's fs.ReadLink,
+// available as of Go 1.25.
+func defaultReadlink(fsys fs.FS, name string) (string, error) {
+	if rl, ok := fsys.(ReadLinkFS); ok {
+		return rl.ReadLink(name)
+	}
+	return fs.ReadLink(fsys, name)
+}
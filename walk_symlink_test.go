@@ -0,0 +1,185 @@
+package fswalk
+
+import (
+	"io/fs"
+	"path"
+	"testing"
+	"testing/fstest"
+)
+
+// fakeSymlinkFS layers symbolic links on top of an fstest.MapFS: every
+// path in links is reported as a fs.ModeSymlink entry whose Stat/ReadDir
+// transparently resolve to its target, the way os.DirFS behaves for real
+// symlinks. It implements ReadLinkFS so WalkDirWithOptions can resolve
+// link targets without a custom Readlink.
+type fakeSymlinkFS struct {
+	fstest.MapFS
+	links map[string]string
+}
+
+func (f fakeSymlinkFS) ReadLink(name string) (string, error) {
+	target, ok := f.links[name]
+	if !ok {
+		return "", fs.ErrInvalid
+	}
+	return target, nil
+}
+
+func (f fakeSymlinkFS) Stat(name string) (fs.FileInfo, error) {
+	if target, ok := f.links[name]; ok {
+		return fs.Stat(f.MapFS, resolveRelLink(name, target))
+	}
+	return fs.Stat(f.MapFS, name)
+}
+
+func (f fakeSymlinkFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if target, ok := f.links[name]; ok {
+		return fs.ReadDir(f.MapFS, resolveRelLink(name, target))
+	}
+	return fs.ReadDir(f.MapFS, name)
+}
+
+// resolveRelLink resolves a symlink target the way real symlinks do: an
+// absolute target is used as-is, a relative one is resolved against the
+// directory containing the link itself.
+func resolveRelLink(name, target string) string {
+	if path.IsAbs(target) {
+		return path.Clean(target)
+	}
+	return path.Join(path.Dir(name), target)
+}
+
+func newFakeSymlinkFS(links map[string]string, mfs fstest.MapFS) fakeSymlinkFS {
+	for name := range links {
+		mfs[name] = &fstest.MapFile{Mode: fs.ModeSymlink}
+	}
+	return fakeSymlinkFS{MapFS: mfs, links: links}
+}
+
+func TestWalkDirWithOptionsFollowsSymlinks(t *testing.T) {
+	mfs := fstest.MapFS{"a/f0": &fstest.MapFile{Data: []byte("x")}}
+	ffs := newFakeSymlinkFS(map[string]string{"link": "a"}, mfs)
+
+	var files []string
+	err := WalkDirWithOptions(ffs, ".", WalkDirOptions{FollowSymlinks: true},
+		func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && d.Type()&fs.ModeSymlink == 0 {
+				files = append(files, path)
+			}
+			return nil
+		},
+		func(path string, d fs.DirEntry) error { return nil },
+	)
+	if err != nil {
+		t.Fatalf("WalkDirWithOptions: %v", err)
+	}
+	want := []string{"a/f0", "link/f0"}
+	if len(files) != len(want) {
+		t.Fatalf("got %v, want %v", files, want)
+	}
+	for i := range want {
+		if files[i] != want[i] {
+			t.Fatalf("got %v, want %v", files, want)
+		}
+	}
+}
+
+func TestWalkDirWithOptionsSymlinkCycle(t *testing.T) {
+	// link1 and link2 both target "a": following the second one must be
+	// recognized as revisiting a target already reached through link1.
+	mfs := fstest.MapFS{"a/f0": &fstest.MapFile{Data: []byte("x")}}
+	ffs := newFakeSymlinkFS(map[string]string{"link1": "a", "link2": "a"}, mfs)
+
+	var cycles int
+	err := WalkDirWithOptions(ffs, ".", WalkDirOptions{FollowSymlinks: true},
+		func(path string, d fs.DirEntry, err error) error {
+			if err == ErrSymlinkCycle {
+				cycles++
+				return fs.SkipDir
+			}
+			return err
+		},
+		func(path string, d fs.DirEntry) error { return nil },
+	)
+	if err != nil {
+		t.Fatalf("WalkDirWithOptions: %v", err)
+	}
+	if cycles == 0 {
+		t.Errorf("expected at least one ErrSymlinkCycle, got none")
+	}
+}
+
+func TestWalkDirWithOptionsSymlinkCycleSkipDirDoesNotDropSiblings(t *testing.T) {
+	// link1 and link2 both target "a"; zzz is a sibling that sorts after
+	// both links and must still be walked, with doneDirFn(".") still
+	// firing, even though the handler returns fs.SkipDir for link2's
+	// cycle.
+	mfs := fstest.MapFS{
+		"a/f0": &fstest.MapFile{Data: []byte("x")},
+		"zzz":  &fstest.MapFile{Data: []byte("x")},
+	}
+	ffs := newFakeSymlinkFS(map[string]string{"link1": "a", "link2": "a"}, mfs)
+
+	var visited []string
+	var doneRoot bool
+	err := WalkDirWithOptions(ffs, ".", WalkDirOptions{FollowSymlinks: true},
+		func(path string, d fs.DirEntry, err error) error {
+			visited = append(visited, path)
+			if err == ErrSymlinkCycle {
+				return fs.SkipDir
+			}
+			return err
+		},
+		func(path string, d fs.DirEntry) error {
+			if path == "." {
+				doneRoot = true
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("WalkDirWithOptions: %v", err)
+	}
+
+	found := false
+	for _, p := range visited {
+		if p == "zzz" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("zzz was dropped from the walk after link2's cycle returned fs.SkipDir; visited %v", visited)
+	}
+	if !doneRoot {
+		t.Errorf("doneDirFn(\".\", ...) was never called after link2's cycle returned fs.SkipDir")
+	}
+}
+
+func TestWalkDirWithOptionsMaxDepth(t *testing.T) {
+	mfs := fstest.MapFS{
+		"a/b/c/f0": &fstest.MapFile{Data: []byte("x")},
+	}
+
+	var paths []string
+	err := WalkDirWithOptions(mfs, ".", WalkDirOptions{MaxDepth: 2},
+		func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			paths = append(paths, path)
+			return nil
+		},
+		func(path string, d fs.DirEntry) error { return nil },
+	)
+	if err != nil {
+		t.Fatalf("WalkDirWithOptions: %v", err)
+	}
+	for _, p := range paths {
+		if p == "a/b/c" || p == "a/b/c/f0" {
+			t.Errorf("MaxDepth: walked %q, which is beyond depth 2", p)
+		}
+	}
+}